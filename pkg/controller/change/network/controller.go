@@ -15,6 +15,9 @@
 package network
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/onosproject/onos-config/pkg/controller"
 	devicechangestore "github.com/onosproject/onos-config/pkg/store/change/device"
 	networkchangestore "github.com/onosproject/onos-config/pkg/store/change/network"
@@ -24,16 +27,32 @@ import (
 	changetypes "github.com/onosproject/onos-config/pkg/types/change"
 	devicetypes "github.com/onosproject/onos-config/pkg/types/change/device"
 	networktypes "github.com/onosproject/onos-config/pkg/types/change/network"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
 )
 
+var log = logging.GetLogger("controller", "change", "network")
+
 // NewController returns a new config controller
 func NewController(leadership leadershipstore.Store, deviceStore devicestore.Store, networkChanges networkchangestore.Store, deviceChanges devicechangestore.Store) *controller.Controller {
 	c := controller.NewController()
 	c.Activate(&controller.LeadershipActivator{
 		Store: leadership,
 	})
+
+	// The index is maintained incrementally from here on by the Watcher, but
+	// it starts out empty. Rebuild it from the store - and wait for that to
+	// finish - before wiring up the Watcher or starting the reconcile loop,
+	// so canApplyChange never sees a change as unblocked just because the
+	// index hasn't caught up to it yet
+	pending := newPendingDeviceIndex()
+	if err := pending.Rebuild(context.Background(), networkChanges); err != nil {
+		log.Errorf("Failed rebuilding pending device change index: %s", err)
+	}
+
 	c.Watch(&Watcher{
-		Store: networkChanges,
+		Store:   networkChanges,
+		pending: pending,
 	})
 	c.Watch(&DeviceWatcher{
 		DeviceStore: deviceStore,
@@ -42,6 +61,13 @@ func NewController(leadership leadershipstore.Store, deviceStore devicestore.Sto
 	c.Reconcile(&Reconciler{
 		networkChanges: networkChanges,
 		deviceChanges:  deviceChanges,
+		devices:        deviceStore,
+		pending:        pending,
+		deviceReconciler: &DeviceReconciler{
+			devices:        deviceStore,
+			networkChanges: networkChanges,
+			deviceChanges:  deviceChanges,
+		},
 	})
 	return c
 }
@@ -50,13 +76,21 @@ func NewController(leadership leadershipstore.Store, deviceStore devicestore.Sto
 type Reconciler struct {
 	networkChanges networkchangestore.Store
 	deviceChanges  devicechangestore.Store
-	// changeIndex is the index of the highest sequential network change applied
-	changeIndex networktypes.Index
+	devices        devicestore.Store
+	// deviceReconciler handles IDs that DeviceWatcher forwards for device
+	// resync rather than network change reconciliation: a device's own ID
+	// when it transitions to CONNECTED. Reconcile tries a network change
+	// lookup first and falls through to deviceReconciler only when the ID
+	// doesn't resolve to one, since the two are delivered onto the same queue
+	deviceReconciler *DeviceReconciler
+	// pending is the incrementally-maintained index of pending/running
+	// network changes by target device, maintained by the Watcher
+	pending *pendingDeviceIndex
 }
 
 // Reconcile reconciles the state of a network configuration
-func (r *Reconciler) Reconcile(id types.ID) (bool, error) {
-	change, err := r.networkChanges.Get(networktypes.ID(id))
+func (r *Reconciler) Reconcile(ctx context.Context, id types.ID) (bool, error) {
+	change, err := r.networkChanges.Get(ctx, networktypes.ID(id))
 	if err != nil {
 		return false, err
 	}
@@ -64,35 +98,56 @@ func (r *Reconciler) Reconcile(id types.ID) (bool, error) {
 	// Handle the change for each phase
 	if change != nil {
 		// For all phases, ensure device changes have been created in the device change store
-		succeeded, err := r.ensureDeviceChanges(change)
+		succeeded, err := r.ensureDeviceChanges(ctx, change)
 		if succeeded || err != nil {
 			return succeeded, err
 		}
 
 		switch change.Status.Phase {
 		case changetypes.Phase_CHANGE:
-			return r.reconcileChange(change)
+			return r.reconcileChange(ctx, change)
 		case changetypes.Phase_ROLLBACK:
-			return r.reconcileRollback(change)
+			return r.reconcileRollback(ctx, change)
 		}
+		return true, nil
 	}
-	return true, nil
+
+	// id isn't a network change; it may be a device that DeviceWatcher
+	// forwarded for resync
+	return r.deviceReconciler.Reconcile(ctx, id)
 }
 
 // ensureDeviceChanges ensures device changes have been created for all changes in the network change
-func (r *Reconciler) ensureDeviceChanges(config *networktypes.NetworkChange) (bool, error) {
+func (r *Reconciler) ensureDeviceChanges(ctx context.Context, config *networktypes.NetworkChange) (bool, error) {
 	// Loop through changes and create if necessary
 	updated := false
 	for _, change := range config.Changes {
 		if change.ID == "" {
+			// Key is derived from the network change and device IDs so that
+			// retrying ensureDeviceChanges after losing a revision race does
+			// not create a duplicate device change for the same device.
+			key := fmt.Sprintf("%s:%s", config.ID, change.DeviceID)
 			deviceChange := &devicetypes.Change{
+				Key:             key,
 				NetworkChangeID: types.ID(config.ID),
 				DeviceID:        change.DeviceID,
 				DeviceVersion:   change.DeviceVersion,
 				Values:          change.Values,
 			}
-			if err := r.deviceChanges.Create(deviceChange); err != nil {
-				return false, err
+			if err := r.deviceChanges.Create(ctx, deviceChange); err != nil {
+				if !errors.IsAlreadyExists(err) {
+					return false, err
+				}
+				// A previous attempt already created the device change; look
+				// it up by its idempotency key rather than using this local,
+				// never-persisted struct, whose ID and Index are still zero.
+				existing, err := r.deviceChanges.GetByKey(ctx, key)
+				if err != nil {
+					return false, err
+				} else if existing == nil {
+					return false, fmt.Errorf("device change with key %s reported as existing but not found", key)
+				}
+				deviceChange = existing
 			}
 			change.ID = deviceChange.ID
 			change.Index = deviceChange.Index
@@ -102,30 +157,57 @@ func (r *Reconciler) ensureDeviceChanges(config *networktypes.NetworkChange) (bo
 
 	// If indexes have been updated, store the indexes first in the network change
 	if updated {
-		if err := r.networkChanges.Update(config); err != nil {
+		ok, err := r.updateNetworkChange(ctx, config)
+		if !ok || err != nil {
 			return false, err
 		}
 	}
 	return updated, nil
 }
 
+// updateNetworkChange updates the given network change in the store, returning
+// (false, nil) if the update lost a revision race rather than surfacing the
+// conflict as an error, so the caller simply requeues and retries
+func (r *Reconciler) updateNetworkChange(ctx context.Context, change *networktypes.NetworkChange) (bool, error) {
+	if err := r.networkChanges.Update(ctx, change); err != nil {
+		if errors.IsConflict(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// updateDeviceChange updates the given device change in the store, returning
+// (false, nil) if the update lost a revision race rather than surfacing the
+// conflict as an error, so the caller simply requeues and retries
+func (r *Reconciler) updateDeviceChange(ctx context.Context, change *devicetypes.Change) (bool, error) {
+	if err := r.deviceChanges.Update(ctx, change); err != nil {
+		if errors.IsConflict(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 // reconcileChange reconciles a change in the CHANGE phase
-func (r *Reconciler) reconcileChange(change *networktypes.NetworkChange) (bool, error) {
+func (r *Reconciler) reconcileChange(ctx context.Context, change *networktypes.NetworkChange) (bool, error) {
 	// Handle each possible state of the phase
 	switch change.Status.State {
 	case changetypes.State_PENDING:
-		return r.reconcilePendingChange(change)
+		return r.reconcilePendingChange(ctx, change)
 	case changetypes.State_RUNNING:
-		return r.reconcileRunningChange(change)
+		return r.reconcileRunningChange(ctx, change)
 	default:
 		return true, nil
 	}
 }
 
 // reconcilePendingChange reconciles a change in the PENDING state during the CHANGE phase
-func (r *Reconciler) reconcilePendingChange(change *networktypes.NetworkChange) (bool, error) {
+func (r *Reconciler) reconcilePendingChange(ctx context.Context, change *networktypes.NetworkChange) (bool, error) {
 	// Determine whether the change can be applied
-	canApply, err := r.canApplyChange(change)
+	canApply, err := r.canApplyChange(ctx, change)
 	if err != nil {
 		return false, err
 	} else if !canApply {
@@ -134,45 +216,45 @@ func (r *Reconciler) reconcilePendingChange(change *networktypes.NetworkChange)
 
 	// If the change can be applied, update the change state to RUNNING
 	change.Status.State = changetypes.State_RUNNING
-	if err := r.networkChanges.Update(change); err != nil {
+	return r.updateNetworkChange(ctx, change)
+}
+
+// canApplyChange returns a bool indicating whether the change can be applied.
+// Rather than re-scanning the full change history, it consults the
+// incrementally-maintained pendingDeviceIndex: the change can apply only if
+// none of its target devices has an earlier PENDING or RUNNING change ahead
+// of it
+func (r *Reconciler) canApplyChange(ctx context.Context, change *networktypes.NetworkChange) (bool, error) {
+	ready, err := r.devicesReady(ctx, change)
+	if !ready || err != nil {
 		return false, err
 	}
-	return true, nil
-}
 
-// canApplyChange returns a bool indicating whether the change can be applied
-func (r *Reconciler) canApplyChange(change *networktypes.NetworkChange) (bool, error) {
-	sequential := true
-	for index := r.changeIndex; index < change.Index; index++ {
-		priorChange, err := r.networkChanges.GetByIndex(index)
-		if err != nil {
-			return false, err
-		} else if priorChange != nil {
-			if priorChange.Status.State == changetypes.State_PENDING || priorChange.Status.State == changetypes.State_RUNNING {
-				if isIntersectingChange(change, priorChange) {
-					return false, nil
-				}
-				sequential = false
-			} else {
-				if sequential {
-					r.changeIndex++
-				}
-			}
+	for _, changeValue := range change.Changes {
+		if head, ok := r.pending.Head(changeValue.DeviceID); ok && head < change.Index {
+			return false, nil
 		}
 	}
 	return true, nil
 }
 
 // reconcileRunningChange reconciles a change in the RUNNING state during the CHANGE phase
-func (r *Reconciler) reconcileRunningChange(change *networktypes.NetworkChange) (bool, error) {
+func (r *Reconciler) reconcileRunningChange(ctx context.Context, change *networktypes.NetworkChange) (bool, error) {
+	// Re-check device readiness in case a target device went away or started
+	// reconciling after the change was put into the RUNNING state
+	ready, err := r.devicesReady(ctx, change)
+	if !ready || err != nil {
+		return false, err
+	}
+
 	// Get the current state of all device changes for the change
-	deviceChanges, err := r.getDeviceChanges(change)
+	deviceChanges, err := r.getDeviceChanges(ctx, change)
 	if err != nil {
 		return false, err
 	}
 
 	// Ensure the device changes are being applied
-	succeeded, err := r.ensureDeviceChangesRunning(deviceChanges)
+	succeeded, err := r.ensureDeviceChangesRunning(ctx, deviceChanges)
 	if succeeded || err != nil {
 		return succeeded, err
 	}
@@ -180,16 +262,13 @@ func (r *Reconciler) reconcileRunningChange(change *networktypes.NetworkChange)
 	// If all device changes are complete, mark the network change complete
 	if r.isDeviceChangesComplete(deviceChanges) {
 		change.Status.State = changetypes.State_COMPLETE
-		if err := r.networkChanges.Update(change); err != nil {
-			return false, err
-		}
-		return true, nil
+		return r.updateNetworkChange(ctx, change)
 	}
 
 	// If a device change failed, rollback pending changes and requeue the change
 	if r.isDeviceChangesFailed(deviceChanges) {
 		// Ensure changes that have not failed are being rolled back
-		succeeded, err = r.ensureDeviceChangeRollbacksRunning(deviceChanges)
+		succeeded, err = r.ensureDeviceChangeRollbacksRunning(ctx, deviceChanges)
 		if succeeded || err != nil {
 			return succeeded, err
 		}
@@ -198,7 +277,7 @@ func (r *Reconciler) reconcileRunningChange(change *networktypes.NetworkChange)
 		if r.isDeviceChangeRollbacksComplete(deviceChanges) {
 			change.Status.State = changetypes.State_PENDING
 			change.Status.Reason = changetypes.Reason_ERROR
-			if err := r.networkChanges.Update(change); err != nil {
+			if _, err := r.updateNetworkChange(ctx, change); err != nil {
 				return false, err
 			}
 		}
@@ -207,13 +286,14 @@ func (r *Reconciler) reconcileRunningChange(change *networktypes.NetworkChange)
 }
 
 // ensureDeviceChangesRunning ensures device changes are in the running state
-func (r *Reconciler) ensureDeviceChangesRunning(changes []*devicetypes.Change) (bool, error) {
+func (r *Reconciler) ensureDeviceChangesRunning(ctx context.Context, changes []*devicetypes.Change) (bool, error) {
 	// Ensure all device changes are being applied
 	updated := false
 	for _, deviceChange := range changes {
 		if deviceChange.Status.State == changetypes.State_PENDING {
 			deviceChange.Status.State = changetypes.State_RUNNING
-			if err := r.deviceChanges.Update(deviceChange); err != nil {
+			ok, err := r.updateDeviceChange(ctx, deviceChange)
+			if !ok || err != nil {
 				return false, err
 			}
 			updated = true
@@ -223,10 +303,10 @@ func (r *Reconciler) ensureDeviceChangesRunning(changes []*devicetypes.Change) (
 }
 
 // getDeviceChanges gets the device changes for the given network change
-func (r *Reconciler) getDeviceChanges(change *networktypes.NetworkChange) ([]*devicetypes.Change, error) {
+func (r *Reconciler) getDeviceChanges(ctx context.Context, change *networktypes.NetworkChange) ([]*devicetypes.Change, error) {
 	deviceChanges := make([]*devicetypes.Change, len(change.Changes))
 	for i, changeReq := range change.Changes {
-		deviceChange, err := r.deviceChanges.Get(changeReq.ID)
+		deviceChange, err := r.deviceChanges.Get(ctx, changeReq.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -256,13 +336,14 @@ func (r *Reconciler) isDeviceChangesFailed(changes []*devicetypes.Change) bool {
 }
 
 // ensureDeviceChangeRollbacksRunning ensures RUNNING or COMPLETE device changes are being rolled back
-func (r *Reconciler) ensureDeviceChangeRollbacksRunning(changes []*devicetypes.Change) (bool, error) {
+func (r *Reconciler) ensureDeviceChangeRollbacksRunning(ctx context.Context, changes []*devicetypes.Change) (bool, error) {
 	updated := false
 	for _, deviceChange := range changes {
 		if deviceChange.Status.Phase == changetypes.Phase_CHANGE && deviceChange.Status.State != changetypes.State_FAILED {
 			deviceChange.Status.Phase = changetypes.Phase_ROLLBACK
 			deviceChange.Status.State = changetypes.State_RUNNING
-			if err := r.deviceChanges.Update(deviceChange); err != nil {
+			ok, err := r.updateDeviceChange(ctx, deviceChange)
+			if !ok || err != nil {
 				return false, err
 			}
 			updated = true
@@ -282,9 +363,9 @@ func (r *Reconciler) isDeviceChangeRollbacksComplete(changes []*devicetypes.Chan
 }
 
 // reconcileRollback reconciles a change in the ROLLBACK phase
-func (r *Reconciler) reconcileRollback(change *networktypes.NetworkChange) (bool, error) {
+func (r *Reconciler) reconcileRollback(ctx context.Context, change *networktypes.NetworkChange) (bool, error) {
 	// Ensure the device changes are in the ROLLBACK phase
-	updated, err := r.ensureDeviceRollbacks(change)
+	updated, err := r.ensureDeviceRollbacks(ctx, change)
 	if updated || err != nil {
 		return updated, err
 	}
@@ -292,20 +373,20 @@ func (r *Reconciler) reconcileRollback(change *networktypes.NetworkChange) (bool
 	// Handle each possible state of the phase
 	switch change.Status.State {
 	case changetypes.State_PENDING:
-		return r.reconcilePendingRollback(change)
+		return r.reconcilePendingRollback(ctx, change)
 	case changetypes.State_RUNNING:
-		return r.reconcileRunningRollback(change)
+		return r.reconcileRunningRollback(ctx, change)
 	default:
 		return true, nil
 	}
 }
 
 // ensureDeviceRollbacks ensures device rollbacks are pending
-func (r *Reconciler) ensureDeviceRollbacks(change *networktypes.NetworkChange) (bool, error) {
+func (r *Reconciler) ensureDeviceRollbacks(ctx context.Context, change *networktypes.NetworkChange) (bool, error) {
 	// Ensure all device changes are being rolled back
 	updated := false
 	for _, changeReq := range change.Changes {
-		deviceChange, err := r.deviceChanges.Get(changeReq.ID)
+		deviceChange, err := r.deviceChanges.Get(ctx, changeReq.ID)
 		if err != nil {
 			return false, err
 		}
@@ -313,7 +394,8 @@ func (r *Reconciler) ensureDeviceRollbacks(change *networktypes.NetworkChange) (
 		if deviceChange.Status.Phase != changetypes.Phase_ROLLBACK {
 			deviceChange.Status.Phase = changetypes.Phase_ROLLBACK
 			deviceChange.Status.State = changetypes.State_PENDING
-			if err := r.deviceChanges.Update(deviceChange); err != nil {
+			ok, err := r.updateDeviceChange(ctx, deviceChange)
+			if !ok || err != nil {
 				return false, err
 			}
 			updated = true
@@ -323,9 +405,9 @@ func (r *Reconciler) ensureDeviceRollbacks(change *networktypes.NetworkChange) (
 }
 
 // reconcilePendingRollback reconciles a change in the PENDING state during the ROLLBACK phase
-func (r *Reconciler) reconcilePendingRollback(change *networktypes.NetworkChange) (bool, error) {
+func (r *Reconciler) reconcilePendingRollback(ctx context.Context, change *networktypes.NetworkChange) (bool, error) {
 	// Determine whether the rollback can be applied
-	canApply, err := r.canApplyRollback(change)
+	canApply, err := r.canApplyRollback(ctx, change)
 	if err != nil {
 		return false, err
 	} else if !canApply {
@@ -334,21 +416,21 @@ func (r *Reconciler) reconcilePendingRollback(change *networktypes.NetworkChange
 
 	// If the rollback can be applied, update the change state to RUNNING
 	change.Status.State = changetypes.State_RUNNING
-	if err := r.networkChanges.Update(change); err != nil {
-		return false, err
-	}
-	return true, nil
+	return r.updateNetworkChange(ctx, change)
 }
 
 // canApplyRollback returns a bool indicating whether the rollback can be applied
-func (r *Reconciler) canApplyRollback(change *networktypes.NetworkChange) (bool, error) {
-	lastIndex, err := r.networkChanges.LastIndex()
+func (r *Reconciler) canApplyRollback(ctx context.Context, change *networktypes.NetworkChange) (bool, error) {
+	lastIndex, err := r.networkChanges.LastIndex(ctx)
 	if err != nil {
 		return false, err
 	}
 
 	for index := change.Index + 1; index <= lastIndex; index++ {
-		futureChange, err := r.networkChanges.GetByIndex(index)
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		futureChange, err := r.networkChanges.GetByIndex(ctx, index)
 		if err != nil {
 			return false, err
 		} else if futureChange != nil && isIntersectingChange(change, futureChange) && futureChange.Status.State != changetypes.State_COMPLETE && futureChange.Status.State != changetypes.State_FAILED {
@@ -359,15 +441,15 @@ func (r *Reconciler) canApplyRollback(change *networktypes.NetworkChange) (bool,
 }
 
 // reconcileRunningRollback reconciles a change in the RUNNING state during the ROLLBACK phase
-func (r *Reconciler) reconcileRunningRollback(change *networktypes.NetworkChange) (bool, error) {
+func (r *Reconciler) reconcileRunningRollback(ctx context.Context, change *networktypes.NetworkChange) (bool, error) {
 	// Ensure the device rollbacks are running
-	succeeded, err := r.ensureDeviceRollbacksRunning(change)
+	succeeded, err := r.ensureDeviceRollbacksRunning(ctx, change)
 	if succeeded || err != nil {
 		return succeeded, err
 	}
 
 	// If the rollback is complete, update the change state. Otherwise discard the change.
-	complete, err := r.isRollbackComplete(change)
+	complete, err := r.isRollbackComplete(ctx, change)
 	if err != nil {
 		return false, err
 	} else if !complete {
@@ -375,24 +457,22 @@ func (r *Reconciler) reconcileRunningRollback(change *networktypes.NetworkChange
 	}
 
 	change.Status.State = changetypes.State_COMPLETE
-	if err := r.networkChanges.Update(change); err != nil {
-		return false, nil
-	}
-	return true, nil
+	return r.updateNetworkChange(ctx, change)
 }
 
 // ensureDeviceRollbacksRunning ensures device rollbacks are in the running state
-func (r *Reconciler) ensureDeviceRollbacksRunning(change *networktypes.NetworkChange) (bool, error) {
+func (r *Reconciler) ensureDeviceRollbacksRunning(ctx context.Context, change *networktypes.NetworkChange) (bool, error) {
 	updated := false
 	for _, changeReq := range change.Changes {
-		deviceChange, err := r.deviceChanges.Get(changeReq.ID)
+		deviceChange, err := r.deviceChanges.Get(ctx, changeReq.ID)
 		if err != nil {
 			return false, err
 		}
 
 		if deviceChange.Status.State == changetypes.State_PENDING {
 			deviceChange.Status.State = changetypes.State_RUNNING
-			if err := r.deviceChanges.Update(deviceChange); err != nil {
+			ok, err := r.updateDeviceChange(ctx, deviceChange)
+			if !ok || err != nil {
 				return false, err
 			}
 			updated = true
@@ -402,10 +482,10 @@ func (r *Reconciler) ensureDeviceRollbacksRunning(change *networktypes.NetworkCh
 }
 
 // isRollbackComplete determines whether a rollback is complete
-func (r *Reconciler) isRollbackComplete(change *networktypes.NetworkChange) (bool, error) {
+func (r *Reconciler) isRollbackComplete(ctx context.Context, change *networktypes.NetworkChange) (bool, error) {
 	complete := 0
 	for _, changeReq := range change.Changes {
-		deviceChange, err := r.deviceChanges.Get(changeReq.ID)
+		deviceChange, err := r.deviceChanges.Get(ctx, changeReq.ID)
 		if err != nil {
 			return false, err
 		}
@@ -429,4 +509,4 @@ func isIntersectingChange(config *networktypes.NetworkChange, history *networkty
 	return false
 }
 
-var _ controller.Reconciler = &Reconciler{}
\ No newline at end of file
+var _ controller.Reconciler = &Reconciler{}
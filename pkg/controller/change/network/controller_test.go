@@ -0,0 +1,165 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	networkchangestore "github.com/onosproject/onos-config/pkg/store/change/network"
+	devicestore "github.com/onosproject/onos-config/pkg/store/device"
+	"github.com/onosproject/onos-config/pkg/types"
+	changetypes "github.com/onosproject/onos-config/pkg/types/change"
+	networktypes "github.com/onosproject/onos-config/pkg/types/change/network"
+	devicetopo "github.com/onosproject/onos-config/pkg/types/device"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// readyDeviceStore reports every device as connected, so tests unrelated to
+// device readiness aren't blocked by the canApplyChange/reconcileRunningChange
+// precondition gate.
+type readyDeviceStore struct{}
+
+func (s *readyDeviceStore) Get(ctx context.Context, id devicetopo.ID) (*devicetopo.Device, error) {
+	return &devicetopo.Device{ID: id, State: devicetopo.DeviceState_DEVICE_CONNECTED}, nil
+}
+
+func (s *readyDeviceStore) Update(ctx context.Context, device *devicetopo.Device) error {
+	return nil
+}
+
+func (s *readyDeviceStore) Watch(ctx context.Context, ch chan<- devicestore.Event) error {
+	return nil
+}
+
+// cancelingNetworkChangeStore returns a valid change on the first Get, then
+// cancels the test context before the Reconciler can perform its next store
+// operation.
+type cancelingNetworkChangeStore struct {
+	networkChange *networktypes.NetworkChange
+	cancel        context.CancelFunc
+	gets          int
+}
+
+func (s *cancelingNetworkChangeStore) Get(ctx context.Context, id networktypes.ID) (*networktypes.NetworkChange, error) {
+	s.gets++
+	if s.gets > 1 {
+		s.cancel()
+		return nil, ctx.Err()
+	}
+	return s.networkChange, nil
+}
+
+func (s *cancelingNetworkChangeStore) GetByIndex(ctx context.Context, index networktypes.Index) (*networktypes.NetworkChange, error) {
+	return nil, nil
+}
+
+func (s *cancelingNetworkChangeStore) Create(ctx context.Context, change *networktypes.NetworkChange) error {
+	return nil
+}
+
+func (s *cancelingNetworkChangeStore) Update(ctx context.Context, change *networktypes.NetworkChange) error {
+	s.cancel()
+	return ctx.Err()
+}
+
+func (s *cancelingNetworkChangeStore) LastIndex(ctx context.Context) (networktypes.Index, error) {
+	return s.networkChange.Index, nil
+}
+
+func (s *cancelingNetworkChangeStore) Watch(ctx context.Context, ch chan<- networkchangestore.Event) error {
+	return nil
+}
+
+// TestReconcileCanceledContext verifies that cancelling the context passed to
+// Reconcile aborts the reconcile loop between store operations rather than
+// continuing to mutate the network change.
+func TestReconcileCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	change := &networktypes.NetworkChange{
+		ID:    "network-change-1",
+		Index: 1,
+		Changes: []*networktypes.ChangeValue{
+			{ID: "device-change-1", Index: 1, DeviceID: "device-1"},
+		},
+	}
+	change.Status.Phase = changetypes.Phase_CHANGE
+	change.Status.State = changetypes.State_PENDING
+
+	networkChanges := &cancelingNetworkChangeStore{
+		networkChange: change,
+		cancel:        cancel,
+	}
+
+	r := &Reconciler{networkChanges: networkChanges, devices: &readyDeviceStore{}, pending: newPendingDeviceIndex()}
+
+	succeeded, err := r.Reconcile(ctx, types.ID(change.ID))
+	assert.False(t, succeeded)
+	assert.Error(t, err)
+	assert.Error(t, ctx.Err())
+}
+
+// conflictingNetworkChangeStore returns a conflict error from Update, simulating a
+// revision bump made by a competing controller or admin API call.
+type conflictingNetworkChangeStore struct {
+	networkChange *networktypes.NetworkChange
+}
+
+func (s *conflictingNetworkChangeStore) Get(ctx context.Context, id networktypes.ID) (*networktypes.NetworkChange, error) {
+	return s.networkChange, nil
+}
+
+func (s *conflictingNetworkChangeStore) GetByIndex(ctx context.Context, index networktypes.Index) (*networktypes.NetworkChange, error) {
+	return nil, nil
+}
+
+func (s *conflictingNetworkChangeStore) Create(ctx context.Context, change *networktypes.NetworkChange) error {
+	return nil
+}
+
+func (s *conflictingNetworkChangeStore) Update(ctx context.Context, change *networktypes.NetworkChange) error {
+	return errors.NewConflict("network change %s was updated concurrently", change.ID)
+}
+
+func (s *conflictingNetworkChangeStore) LastIndex(ctx context.Context) (networktypes.Index, error) {
+	return s.networkChange.Index, nil
+}
+
+func (s *conflictingNetworkChangeStore) Watch(ctx context.Context, ch chan<- networkchangestore.Event) error {
+	return nil
+}
+
+// TestReconcilePendingChangeConflict verifies that a revision conflict on the
+// network change store is treated as a retryable no-op rather than an error.
+func TestReconcilePendingChangeConflict(t *testing.T) {
+	change := &networktypes.NetworkChange{
+		ID:    "network-change-1",
+		Index: 1,
+		Changes: []*networktypes.ChangeValue{
+			{ID: "device-change-1", Index: 1, DeviceID: "device-1"},
+		},
+	}
+	change.Status.Phase = changetypes.Phase_CHANGE
+	change.Status.State = changetypes.State_PENDING
+
+	r := &Reconciler{networkChanges: &conflictingNetworkChangeStore{networkChange: change}, devices: &readyDeviceStore{}, pending: newPendingDeviceIndex()}
+
+	succeeded, err := r.reconcilePendingChange(context.Background(), change)
+	assert.NoError(t, err)
+	assert.False(t, succeeded)
+}
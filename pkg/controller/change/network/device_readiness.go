@@ -0,0 +1,61 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"context"
+	"fmt"
+
+	changetypes "github.com/onosproject/onos-config/pkg/types/change"
+	networktypes "github.com/onosproject/onos-config/pkg/types/change/network"
+	devicetopo "github.com/onosproject/onos-config/pkg/types/device"
+)
+
+// devicesReady checks whether all target devices of the given network change
+// are in a state in which a change can be applied to them.
+//
+// For retryable cases -- a device is reconciling after an adapter restart or
+// is momentarily unreachable -- it returns (false, nil) and marks the change
+// with Reason_PhaseChangeRefused so watchers of the change status can tell a
+// device-readiness wait apart from the unrelated "conflicts with a prior
+// change" case handled by canApplyChange. For terminal cases -- the device
+// has been deleted -- it sets Reason_ERROR with a descriptive message, since
+// the change can never be applied.
+func (r *Reconciler) devicesReady(ctx context.Context, change *networktypes.NetworkChange) (bool, error) {
+	for _, changeValue := range change.Changes {
+		device, err := r.devices.Get(ctx, changeValue.DeviceID)
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case device == nil, device.State == devicetopo.DeviceState_DEVICE_DELETING:
+			change.Status.Reason = changetypes.Reason_ERROR
+			change.Status.Message = fmt.Sprintf("cannot apply change: device %s is being deleted", changeValue.DeviceID)
+			if _, err := r.updateNetworkChange(ctx, change); err != nil {
+				return false, err
+			}
+			return false, nil
+		case device.State == devicetopo.DeviceState_DEVICE_RECONCILING, device.State == devicetopo.DeviceState_DEVICE_UNAVAILABLE:
+			change.Status.Reason = changetypes.Reason_PhaseChangeRefused
+			change.Status.Message = fmt.Sprintf("waiting for device %s to become ready", changeValue.DeviceID)
+			if _, err := r.updateNetworkChange(ctx, change); err != nil {
+				return false, err
+			}
+			return false, nil
+		}
+	}
+	return true, nil
+}
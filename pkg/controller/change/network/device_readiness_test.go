@@ -0,0 +1,82 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	devicestore "github.com/onosproject/onos-config/pkg/store/device"
+	changetypes "github.com/onosproject/onos-config/pkg/types/change"
+	networktypes "github.com/onosproject/onos-config/pkg/types/change/network"
+	devicetopo "github.com/onosproject/onos-config/pkg/types/device"
+)
+
+// fakeDeviceStore returns a fixed device state for every device lookup
+type fakeDeviceStore struct {
+	state devicetopo.DeviceState
+}
+
+func (s *fakeDeviceStore) Get(ctx context.Context, id devicetopo.ID) (*devicetopo.Device, error) {
+	return &devicetopo.Device{ID: id, State: s.state}, nil
+}
+
+func (s *fakeDeviceStore) Update(ctx context.Context, device *devicetopo.Device) error {
+	return nil
+}
+
+func (s *fakeDeviceStore) Watch(ctx context.Context, ch chan<- devicestore.Event) error {
+	return nil
+}
+
+func TestDevicesReadyRetryableState(t *testing.T) {
+	change := &networktypes.NetworkChange{
+		ID: "network-change-1",
+		Changes: []*networktypes.ChangeValue{
+			{DeviceID: "device-1"},
+		},
+	}
+
+	r := &Reconciler{
+		networkChanges: &conflictingNetworkChangeStore{networkChange: change},
+		devices:        &fakeDeviceStore{state: devicetopo.DeviceState_DEVICE_RECONCILING},
+	}
+
+	ready, err := r.devicesReady(context.Background(), change)
+	assert.NoError(t, err)
+	assert.False(t, ready)
+	assert.Equal(t, changetypes.Reason_PhaseChangeRefused, change.Status.Reason)
+}
+
+func TestDevicesReadyTerminalState(t *testing.T) {
+	change := &networktypes.NetworkChange{
+		ID: "network-change-1",
+		Changes: []*networktypes.ChangeValue{
+			{DeviceID: "device-1"},
+		},
+	}
+
+	r := &Reconciler{
+		networkChanges: &conflictingNetworkChangeStore{networkChange: change},
+		devices:        &fakeDeviceStore{state: devicetopo.DeviceState_DEVICE_DELETING},
+	}
+
+	ready, err := r.devicesReady(context.Background(), change)
+	assert.NoError(t, err)
+	assert.False(t, ready)
+	assert.Equal(t, changetypes.Reason_ERROR, change.Status.Reason)
+}
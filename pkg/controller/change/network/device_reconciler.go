@@ -0,0 +1,166 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/onosproject/onos-config/pkg/controller"
+	devicechangestore "github.com/onosproject/onos-config/pkg/store/change/device"
+	networkchangestore "github.com/onosproject/onos-config/pkg/store/change/network"
+	devicestore "github.com/onosproject/onos-config/pkg/store/device"
+	"github.com/onosproject/onos-config/pkg/types"
+	changetypes "github.com/onosproject/onos-config/pkg/types/change"
+	devicetypes "github.com/onosproject/onos-config/pkg/types/change/device"
+	networktypes "github.com/onosproject/onos-config/pkg/types/change/network"
+	devicetopo "github.com/onosproject/onos-config/pkg/types/device"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// DeviceReconciler resyncs a device's configuration when it reconnects after
+// being offline. It is the sibling of Reconciler, run from the same
+// Controller via Reconciler.Reconcile so that CONNECTED device IDs and
+// network change IDs, both delivered onto the one queue NewController sets
+// up, are routed to the reconciler that actually understands them. The
+// network change store is the system of record for "what should be
+// configured"; a device that reconnects with LastResyncIndex behind the
+// latest completed network change has missed changes and needs the merged
+// result of those changes replayed to it
+type DeviceReconciler struct {
+	devices        devicestore.Store
+	networkChanges networkchangestore.Store
+	deviceChanges  devicechangestore.Store
+}
+
+// Reconcile resyncs configuration to a device when it transitions to CONNECTED
+func (r *DeviceReconciler) Reconcile(ctx context.Context, id types.ID) (bool, error) {
+	device, err := r.devices.Get(ctx, devicetopo.ID(id))
+	if err != nil {
+		return false, err
+	} else if device == nil || device.State != devicetopo.DeviceState_DEVICE_CONNECTED {
+		return true, nil
+	}
+	return r.resyncDevice(ctx, device)
+}
+
+// resyncDevice replays the effective merged configuration for the device,
+// starting just after its LastResyncIndex cursor, as a single synthetic
+// device change in the RESYNC phase. It does not create a new NetworkChange
+func (r *DeviceReconciler) resyncDevice(ctx context.Context, device *devicetopo.Device) (bool, error) {
+	lastIndex, err := r.networkChanges.LastIndex(ctx)
+	if err != nil {
+		return false, err
+	} else if lastIndex <= device.LastResyncIndex {
+		return true, nil
+	}
+
+	values, appliedIndex, err := r.mergeDeviceValues(ctx, device, lastIndex)
+	if err != nil {
+		return false, err
+	} else if appliedIndex <= device.LastResyncIndex {
+		// Every change since the last resync is still PENDING or RUNNING;
+		// nothing has settled yet for this device to replay
+		return true, nil
+	}
+
+	deviceChange := &devicetypes.Change{
+		// Key makes the resync idempotent: if the reconciler crashes after
+		// creating the device change but before the LastResyncIndex cursor is
+		// advanced, retrying finds the same change already created instead of
+		// enqueuing a duplicate.
+		Key:           fmt.Sprintf("%s:resync:%d", device.ID, appliedIndex),
+		DeviceID:      device.ID,
+		DeviceVersion: device.Version,
+		Values:        values,
+	}
+	deviceChange.Status.Phase = changetypes.Phase_RESYNC
+	deviceChange.Status.State = changetypes.State_PENDING
+	if err := r.deviceChanges.Create(ctx, deviceChange); err != nil && !errors.IsAlreadyExists(err) {
+		return false, err
+	}
+
+	device.LastResyncIndex = appliedIndex
+	if err := r.devices.Update(ctx, device); err != nil {
+		if errors.IsConflict(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// mergeDeviceValues walks the network change history in index order, starting
+// just after the device's LastResyncIndex cursor, and computes the effective
+// value set for the device: later writes override earlier ones for the same
+// path, and a completed ROLLBACK phase removes the values it reverted. Only
+// COMPLETE changes are folded in - a PENDING or RUNNING change hasn't settled
+// on an effective value yet, and a FAILED one never took effect.
+//
+// It also returns the highest index it's safe to advance the device's
+// LastResyncIndex cursor to: the index just before the first change that is
+// still PENDING or RUNNING, or lastIndex if every change up to it has
+// settled. Stopping there - rather than at lastIndex regardless - means a
+// change that's still in flight when the device reconnects gets picked up on
+// a later resync once it completes or fails, instead of being skipped
+// forever because the cursor already passed its index
+func (r *DeviceReconciler) mergeDeviceValues(ctx context.Context, device *devicetopo.Device, lastIndex networktypes.Index) ([]*devicetypes.PathValue, networktypes.Index, error) {
+	merged := make(map[string]*devicetypes.PathValue)
+	appliedIndex := lastIndex
+	for index := device.LastResyncIndex + 1; index <= lastIndex; index++ {
+		change, err := r.networkChanges.GetByIndex(ctx, index)
+		if err != nil {
+			return nil, 0, err
+		} else if change == nil {
+			continue
+		}
+
+		if change.Status.State == changetypes.State_PENDING || change.Status.State == changetypes.State_RUNNING {
+			appliedIndex = index - 1
+			break
+		}
+
+		for _, changeValue := range change.Changes {
+			if changeValue.DeviceID != device.ID {
+				continue
+			}
+
+			if change.Status.Phase == changetypes.Phase_ROLLBACK {
+				if change.Status.State == changetypes.State_COMPLETE {
+					for _, value := range changeValue.Values {
+						delete(merged, value.Path)
+					}
+				}
+				continue
+			}
+
+			if change.Status.State != changetypes.State_COMPLETE {
+				continue
+			}
+
+			for _, value := range changeValue.Values {
+				merged[value.Path] = value
+			}
+		}
+	}
+
+	values := make([]*devicetypes.PathValue, 0, len(merged))
+	for _, value := range merged {
+		values = append(values, value)
+	}
+	return values, appliedIndex, nil
+}
+
+var _ controller.Reconciler = &DeviceReconciler{}
@@ -0,0 +1,235 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	devicechangestore "github.com/onosproject/onos-config/pkg/store/change/device"
+	networkchangestore "github.com/onosproject/onos-config/pkg/store/change/network"
+	devicestore "github.com/onosproject/onos-config/pkg/store/device"
+	"github.com/onosproject/onos-config/pkg/types"
+	changetypes "github.com/onosproject/onos-config/pkg/types/change"
+	devicetypes "github.com/onosproject/onos-config/pkg/types/change/device"
+	networktypes "github.com/onosproject/onos-config/pkg/types/change/network"
+	devicetopo "github.com/onosproject/onos-config/pkg/types/device"
+)
+
+// fakeNetworkChangeHistoryStore serves a fixed, ordered history of network
+// changes indexed by their Index field
+type fakeNetworkChangeHistoryStore struct {
+	byIndex map[networktypes.Index]*networktypes.NetworkChange
+	last    networktypes.Index
+}
+
+func (s *fakeNetworkChangeHistoryStore) Get(ctx context.Context, id networktypes.ID) (*networktypes.NetworkChange, error) {
+	for _, change := range s.byIndex {
+		if change.ID == id {
+			return change, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeNetworkChangeHistoryStore) GetByIndex(ctx context.Context, index networktypes.Index) (*networktypes.NetworkChange, error) {
+	return s.byIndex[index], nil
+}
+
+func (s *fakeNetworkChangeHistoryStore) Create(ctx context.Context, change *networktypes.NetworkChange) error {
+	return nil
+}
+
+func (s *fakeNetworkChangeHistoryStore) Update(ctx context.Context, change *networktypes.NetworkChange) error {
+	return nil
+}
+
+func (s *fakeNetworkChangeHistoryStore) LastIndex(ctx context.Context) (networktypes.Index, error) {
+	return s.last, nil
+}
+
+func (s *fakeNetworkChangeHistoryStore) Watch(ctx context.Context, ch chan<- networkchangestore.Event) error {
+	return nil
+}
+
+// fakeResyncDeviceStore serves a single device and records the last update
+// resyncDevice made to it, including its new LastResyncIndex
+type fakeResyncDeviceStore struct {
+	device *devicetopo.Device
+}
+
+func (s *fakeResyncDeviceStore) Get(ctx context.Context, id devicetopo.ID) (*devicetopo.Device, error) {
+	return s.device, nil
+}
+
+func (s *fakeResyncDeviceStore) Update(ctx context.Context, device *devicetopo.Device) error {
+	s.device = device
+	return nil
+}
+
+func (s *fakeResyncDeviceStore) Watch(ctx context.Context, ch chan<- devicestore.Event) error {
+	return nil
+}
+
+// fakeResyncDeviceChangeStore records created device changes
+type fakeResyncDeviceChangeStore struct {
+	created []*devicetypes.Change
+}
+
+func (s *fakeResyncDeviceChangeStore) Get(ctx context.Context, id devicetypes.ID) (*devicetypes.Change, error) {
+	for _, c := range s.created {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeResyncDeviceChangeStore) GetByKey(ctx context.Context, key string) (*devicetypes.Change, error) {
+	for _, c := range s.created {
+		if c.Key == key {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeResyncDeviceChangeStore) Create(ctx context.Context, change *devicetypes.Change) error {
+	s.created = append(s.created, change)
+	return nil
+}
+
+func (s *fakeResyncDeviceChangeStore) Update(ctx context.Context, change *devicetypes.Change) error {
+	return nil
+}
+
+func (s *fakeResyncDeviceChangeStore) Watch(ctx context.Context, ch chan<- devicechangestore.Event) error {
+	return nil
+}
+
+func TestResyncDeviceMergesLaterWritesOverEarlier(t *testing.T) {
+	deviceID := devicetopo.ID("device-1")
+
+	older := &networktypes.NetworkChange{ID: "change-1", Index: 1}
+	older.Changes = []*networktypes.ChangeValue{
+		{DeviceID: deviceID, Values: []*devicetypes.PathValue{{Path: "/a", Value: []byte("old")}}},
+	}
+	older.Status.Phase = changetypes.Phase_CHANGE
+	older.Status.State = changetypes.State_COMPLETE
+
+	newer := &networktypes.NetworkChange{ID: "change-2", Index: 2}
+	newer.Changes = []*networktypes.ChangeValue{
+		{DeviceID: deviceID, Values: []*devicetypes.PathValue{{Path: "/a", Value: []byte("new")}}},
+	}
+	newer.Status.Phase = changetypes.Phase_CHANGE
+	newer.Status.State = changetypes.State_COMPLETE
+
+	networkChanges := &fakeNetworkChangeHistoryStore{
+		byIndex: map[networktypes.Index]*networktypes.NetworkChange{1: older, 2: newer},
+		last:    2,
+	}
+	deviceChanges := &fakeResyncDeviceChangeStore{}
+	devices := &fakeResyncDeviceStore{device: &devicetopo.Device{ID: deviceID, State: devicetopo.DeviceState_DEVICE_CONNECTED}}
+
+	r := &DeviceReconciler{devices: devices, networkChanges: networkChanges, deviceChanges: deviceChanges}
+
+	succeeded, err := r.Reconcile(context.Background(), types.ID(deviceID))
+	assert.NoError(t, err)
+	assert.True(t, succeeded)
+
+	assert.Len(t, deviceChanges.created, 1)
+	assert.Len(t, deviceChanges.created[0].Values, 1)
+	assert.Equal(t, []byte("new"), deviceChanges.created[0].Values[0].Value)
+	assert.Equal(t, changetypes.Phase_RESYNC, deviceChanges.created[0].Status.Phase)
+	assert.Equal(t, networktypes.Index(2), devices.device.LastResyncIndex)
+}
+
+// TestResyncDeviceStopsAtInFlightChange verifies that a PENDING/RUNNING
+// change partway through the history blocks the merge from advancing past
+// it, and that a later resync pass picks the cursor back up once the change
+// settles to COMPLETE.
+func TestResyncDeviceStopsAtInFlightChange(t *testing.T) {
+	deviceID := devicetopo.ID("device-1")
+
+	complete := &networktypes.NetworkChange{ID: "change-1", Index: 1}
+	complete.Changes = []*networktypes.ChangeValue{
+		{DeviceID: deviceID, Values: []*devicetypes.PathValue{{Path: "/a", Value: []byte("first")}}},
+	}
+	complete.Status.Phase = changetypes.Phase_CHANGE
+	complete.Status.State = changetypes.State_COMPLETE
+
+	inFlight := &networktypes.NetworkChange{ID: "change-2", Index: 2}
+	inFlight.Changes = []*networktypes.ChangeValue{
+		{DeviceID: deviceID, Values: []*devicetypes.PathValue{{Path: "/b", Value: []byte("second")}}},
+	}
+	inFlight.Status.Phase = changetypes.Phase_CHANGE
+	inFlight.Status.State = changetypes.State_RUNNING
+
+	later := &networktypes.NetworkChange{ID: "change-3", Index: 3}
+	later.Changes = []*networktypes.ChangeValue{
+		{DeviceID: deviceID, Values: []*devicetypes.PathValue{{Path: "/c", Value: []byte("third")}}},
+	}
+	later.Status.Phase = changetypes.Phase_CHANGE
+	later.Status.State = changetypes.State_COMPLETE
+
+	networkChanges := &fakeNetworkChangeHistoryStore{
+		byIndex: map[networktypes.Index]*networktypes.NetworkChange{1: complete, 2: inFlight, 3: later},
+		last:    3,
+	}
+	deviceChanges := &fakeResyncDeviceChangeStore{}
+	devices := &fakeResyncDeviceStore{device: &devicetopo.Device{ID: deviceID, State: devicetopo.DeviceState_DEVICE_CONNECTED}}
+
+	r := &DeviceReconciler{devices: devices, networkChanges: networkChanges, deviceChanges: deviceChanges}
+
+	// First pass: change-2 is still RUNNING, so the merge must stop just
+	// before it and the later COMPLETE change-3 must not be applied early
+	succeeded, err := r.Reconcile(context.Background(), types.ID(deviceID))
+	assert.NoError(t, err)
+	assert.True(t, succeeded)
+
+	assert.Len(t, deviceChanges.created, 1)
+	assert.Len(t, deviceChanges.created[0].Values, 1)
+	assert.Equal(t, []byte("first"), deviceChanges.created[0].Values[0].Value)
+	assert.Equal(t, networktypes.Index(1), devices.device.LastResyncIndex)
+
+	// change-2 settles to COMPLETE; a later resync pass should pick the
+	// cursor back up and merge both change-2 and change-3
+	inFlight.Status.State = changetypes.State_COMPLETE
+
+	succeeded, err = r.Reconcile(context.Background(), types.ID(deviceID))
+	assert.NoError(t, err)
+	assert.True(t, succeeded)
+
+	assert.Len(t, deviceChanges.created, 2)
+	assert.Len(t, deviceChanges.created[1].Values, 2)
+	assert.Equal(t, networktypes.Index(3), devices.device.LastResyncIndex)
+}
+
+func TestResyncDeviceSkipsWhenAlreadyCurrent(t *testing.T) {
+	deviceID := devicetopo.ID("device-1")
+
+	networkChanges := &fakeNetworkChangeHistoryStore{byIndex: map[networktypes.Index]*networktypes.NetworkChange{}, last: 5}
+	deviceChanges := &fakeResyncDeviceChangeStore{}
+	devices := &fakeResyncDeviceStore{device: &devicetopo.Device{ID: deviceID, State: devicetopo.DeviceState_DEVICE_CONNECTED, LastResyncIndex: 5}}
+
+	r := &DeviceReconciler{devices: devices, networkChanges: networkChanges, deviceChanges: deviceChanges}
+
+	succeeded, err := r.Reconcile(context.Background(), types.ID(deviceID))
+	assert.NoError(t, err)
+	assert.True(t, succeeded)
+	assert.Empty(t, deviceChanges.created)
+}
@@ -0,0 +1,92 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"context"
+
+	"github.com/onosproject/onos-config/pkg/controller"
+	devicechangestore "github.com/onosproject/onos-config/pkg/store/change/device"
+	devicestore "github.com/onosproject/onos-config/pkg/store/device"
+	"github.com/onosproject/onos-config/pkg/types"
+	devicetopo "github.com/onosproject/onos-config/pkg/types/device"
+)
+
+// DeviceWatcher feeds reconcile IDs from two sources: a device transitioning
+// to CONNECTED (consumed by DeviceReconciler to trigger a resync), and a
+// device change being created or updated (consumed by the network Reconciler,
+// keyed on NetworkChangeID, to re-reconcile the parent network change once
+// one of its device changes progresses)
+type DeviceWatcher struct {
+	DeviceStore devicestore.Store
+	ChangeStore devicechangestore.Store
+	cancel      context.CancelFunc
+}
+
+// Start begins forwarding device and device-change events to ch
+func (w *DeviceWatcher) Start(ch chan<- types.ID) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	deviceEvents := make(chan devicestore.Event)
+	if err := w.DeviceStore.Watch(ctx, deviceEvents); err != nil {
+		cancel()
+		return err
+	}
+	changeEvents := make(chan devicechangestore.Event)
+	if err := w.ChangeStore.Watch(ctx, changeEvents); err != nil {
+		cancel()
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-deviceEvents:
+				if !ok {
+					return
+				}
+				if event.Device.State == devicetopo.DeviceState_DEVICE_CONNECTED {
+					send(ctx, ch, types.ID(event.Device.ID))
+				}
+			case event, ok := <-changeEvents:
+				if !ok {
+					return
+				}
+				send(ctx, ch, event.Change.NetworkChangeID)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops forwarding events
+func (w *DeviceWatcher) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+}
+
+// send forwards id to ch, giving up if ctx is done first
+func send(ctx context.Context, ch chan<- types.ID, id types.ID) {
+	select {
+	case ch <- id:
+	case <-ctx.Done():
+	}
+}
+
+var _ controller.Watcher = &DeviceWatcher{}
@@ -0,0 +1,158 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	networkchangestore "github.com/onosproject/onos-config/pkg/store/change/network"
+	changetypes "github.com/onosproject/onos-config/pkg/types/change"
+	networktypes "github.com/onosproject/onos-config/pkg/types/change/network"
+	devicetopo "github.com/onosproject/onos-config/pkg/types/device"
+)
+
+// pendingDeviceIndex tracks, per device, the indices of PENDING or RUNNING
+// network changes that target that device, ordered ascending by index. The
+// Watcher calls Update on every network-change create/update event so
+// canApplyChange can check in O(devices-in-change) whether an earlier,
+// still-pending change intersects the device set, instead of re-scanning the
+// entire change history on every reconcile. The network change store reports
+// no deletions, and the Watcher does not call Remove
+type pendingDeviceIndex struct {
+	mu       sync.Mutex
+	pending  map[devicetopo.ID]*list.List
+	elements map[devicetopo.ID]map[networktypes.Index]*list.Element
+}
+
+func newPendingDeviceIndex() *pendingDeviceIndex {
+	return &pendingDeviceIndex{
+		pending:  make(map[devicetopo.ID]*list.List),
+		elements: make(map[devicetopo.ID]map[networktypes.Index]*list.Element),
+	}
+}
+
+// Update reflects a network change's current status in the index, inserting
+// it into its target devices' pending lists if it's PENDING or RUNNING and
+// removing it otherwise
+func (idx *pendingDeviceIndex) Update(change *networktypes.NetworkChange) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	pending := change.Status.State == changetypes.State_PENDING || change.Status.State == changetypes.State_RUNNING
+	for _, changeValue := range change.Changes {
+		idx.removeLocked(changeValue.DeviceID, change.Index)
+		if pending {
+			idx.insertLocked(changeValue.DeviceID, change.Index)
+		}
+	}
+}
+
+// Remove removes a network change from the index, e.g. on delete
+func (idx *pendingDeviceIndex) Remove(change *networktypes.NetworkChange) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, changeValue := range change.Changes {
+		idx.removeLocked(changeValue.DeviceID, change.Index)
+	}
+}
+
+// Head returns the lowest pending/running network change index for the given
+// device, if any
+func (idx *pendingDeviceIndex) Head(deviceID devicetopo.ID) (networktypes.Index, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	changes, ok := idx.pending[deviceID]
+	if !ok || changes.Front() == nil {
+		return 0, false
+	}
+	return changes.Front().Value.(networktypes.Index), true
+}
+
+// Rebuild discards the in-memory index and reconstructs it from the store.
+// It's called once when the controller starts, since the index is otherwise
+// only ever maintained incrementally by Update/Remove
+func (idx *pendingDeviceIndex) Rebuild(ctx context.Context, store networkchangestore.Store) error {
+	lastIndex, err := store.LastIndex(ctx)
+	if err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	idx.pending = make(map[devicetopo.ID]*list.List)
+	idx.elements = make(map[devicetopo.ID]map[networktypes.Index]*list.Element)
+	idx.mu.Unlock()
+
+	for index := networktypes.Index(1); index <= lastIndex; index++ {
+		change, err := store.GetByIndex(ctx, index)
+		if err != nil {
+			return err
+		} else if change != nil {
+			idx.Update(change)
+		}
+	}
+	return nil
+}
+
+// insertLocked inserts index into deviceID's pending list in ascending order.
+// Callers must hold idx.mu
+func (idx *pendingDeviceIndex) insertLocked(deviceID devicetopo.ID, index networktypes.Index) {
+	changes, ok := idx.pending[deviceID]
+	if !ok {
+		changes = list.New()
+		idx.pending[deviceID] = changes
+		idx.elements[deviceID] = make(map[networktypes.Index]*list.Element)
+	}
+
+	if _, ok := idx.elements[deviceID][index]; ok {
+		return
+	}
+
+	var sibling *list.Element
+	for e := changes.Front(); e != nil; e = e.Next() {
+		if e.Value.(networktypes.Index) > index {
+			sibling = e
+			break
+		}
+	}
+
+	var element *list.Element
+	if sibling != nil {
+		element = changes.InsertBefore(index, sibling)
+	} else {
+		element = changes.PushBack(index)
+	}
+	idx.elements[deviceID][index] = element
+}
+
+// removeLocked removes index from deviceID's pending list, if present.
+// Callers must hold idx.mu
+func (idx *pendingDeviceIndex) removeLocked(deviceID devicetopo.ID, index networktypes.Index) {
+	elements, ok := idx.elements[deviceID]
+	if !ok {
+		return
+	}
+
+	element, ok := elements[index]
+	if !ok {
+		return
+	}
+
+	idx.pending[deviceID].Remove(element)
+	delete(elements, index)
+}
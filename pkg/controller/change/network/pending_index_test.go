@@ -0,0 +1,76 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	changetypes "github.com/onosproject/onos-config/pkg/types/change"
+	networktypes "github.com/onosproject/onos-config/pkg/types/change/network"
+	devicetopo "github.com/onosproject/onos-config/pkg/types/device"
+)
+
+func pendingChange(id networktypes.ID, index networktypes.Index, state changetypes.State, deviceIDs ...devicetopo.ID) *networktypes.NetworkChange {
+	change := &networktypes.NetworkChange{ID: id, Index: index}
+	for _, deviceID := range deviceIDs {
+		change.Changes = append(change.Changes, &networktypes.ChangeValue{DeviceID: deviceID})
+	}
+	change.Status.State = state
+	return change
+}
+
+// TestPendingDeviceIndexHeadMatchesLinearScan verifies that the index's Head
+// lookup agrees with what the old O(n) scan over change history would have
+// found: the lowest index of a still-pending/running change touching a
+// device.
+func TestPendingDeviceIndexHeadMatchesLinearScan(t *testing.T) {
+	idx := newPendingDeviceIndex()
+
+	idx.Update(pendingChange("change-1", 1, changetypes.State_COMPLETE, "device-1"))
+	idx.Update(pendingChange("change-2", 2, changetypes.State_PENDING, "device-1", "device-2"))
+	idx.Update(pendingChange("change-3", 3, changetypes.State_RUNNING, "device-2"))
+
+	head, ok := idx.Head("device-1")
+	assert.True(t, ok)
+	assert.Equal(t, networktypes.Index(2), head)
+
+	head, ok = idx.Head("device-2")
+	assert.True(t, ok)
+	assert.Equal(t, networktypes.Index(2), head)
+
+	_, ok = idx.Head("device-3")
+	assert.False(t, ok)
+}
+
+// TestPendingDeviceIndexUpdateRemovesCompletedChange verifies that a change
+// transitioning to COMPLETE is removed from the index so it no longer blocks
+// later changes targeting the same device.
+func TestPendingDeviceIndexUpdateRemovesCompletedChange(t *testing.T) {
+	idx := newPendingDeviceIndex()
+
+	change := pendingChange("change-1", 1, changetypes.State_RUNNING, "device-1")
+	idx.Update(change)
+
+	_, ok := idx.Head("device-1")
+	assert.True(t, ok)
+
+	change.Status.State = changetypes.State_COMPLETE
+	idx.Update(change)
+
+	_, ok = idx.Head("device-1")
+	assert.False(t, ok)
+}
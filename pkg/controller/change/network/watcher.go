@@ -0,0 +1,69 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"context"
+
+	"github.com/onosproject/onos-config/pkg/controller"
+	networkchangestore "github.com/onosproject/onos-config/pkg/store/change/network"
+	"github.com/onosproject/onos-config/pkg/types"
+)
+
+// Watcher feeds reconcile IDs from the network change store, and keeps
+// pending in sync with the store as changes are created and updated so
+// canApplyChange always sees a consistent view without re-scanning history
+type Watcher struct {
+	Store   networkchangestore.Store
+	pending *pendingDeviceIndex
+	cancel  context.CancelFunc
+}
+
+// Start begins forwarding network change events to ch
+func (w *Watcher) Start(ch chan<- types.ID) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	events := make(chan networkchangestore.Event)
+	if err := w.Store.Watch(ctx, events); err != nil {
+		cancel()
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				w.pending.Update(event.Change)
+				send(ctx, ch, types.ID(event.Change.ID))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops forwarding events
+func (w *Watcher) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+}
+
+var _ controller.Watcher = &Watcher{}
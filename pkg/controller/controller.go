@@ -0,0 +1,149 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package controller provides a generic reconcile loop that drives a
+// Reconciler from a set of Watchers, active only while all registered
+// Activators report active (e.g. while this replica holds leadership)
+package controller
+
+import (
+	"context"
+	"sync"
+
+	"github.com/onosproject/onos-config/pkg/types"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("controller")
+
+// Reconciler reconciles the state of the resource identified by id, toward
+// its desired state. It returns true once no further work is needed, or
+// false (with a nil error) if the caller should requeue id for another
+// attempt, e.g. because a precondition is not yet satisfied
+type Reconciler interface {
+	Reconcile(ctx context.Context, id types.ID) (bool, error)
+}
+
+// Watcher feeds the IDs of changed resources onto ch until Stop is called
+type Watcher interface {
+	Start(ch chan<- types.ID) error
+	Stop()
+}
+
+// Activator feeds the controller's active/inactive transitions onto ch until
+// Stop is called. A Controller with no Activators is always active
+type Activator interface {
+	Start(ch chan<- bool) error
+	Stop()
+}
+
+// Controller drives a Reconciler from a set of Watchers, running only while
+// active. Construct one with NewController, register its Watchers and
+// Activators, then call Reconcile to start the loop
+type Controller struct {
+	mu         sync.Mutex
+	watchers   []Watcher
+	activators []Activator
+	queue      chan types.ID
+	activeCh   chan bool
+	cancel     context.CancelFunc
+}
+
+// NewController returns a new, unstarted Controller
+func NewController() *Controller {
+	return &Controller{
+		queue:    make(chan types.ID, 1024),
+		activeCh: make(chan bool, 1),
+	}
+}
+
+// Watch registers a Watcher with the controller. It may be called either
+// before or after Reconcile
+func (c *Controller) Watch(watcher Watcher) {
+	c.mu.Lock()
+	c.watchers = append(c.watchers, watcher)
+	c.mu.Unlock()
+	if err := watcher.Start(c.queue); err != nil {
+		log.Errorf("Failed starting watcher: %s", err)
+	}
+}
+
+// Activate registers an Activator that gates whether the controller
+// reconciles, e.g. based on leadership
+func (c *Controller) Activate(activator Activator) {
+	c.mu.Lock()
+	c.activators = append(c.activators, activator)
+	c.mu.Unlock()
+	if err := activator.Start(c.activeCh); err != nil {
+		log.Errorf("Failed starting activator: %s", err)
+	}
+}
+
+// Reconcile sets the controller's Reconciler and starts the reconcile loop.
+// The controller is active by default and becomes inactive only once an
+// Activator reports false
+func (c *Controller) Reconcile(reconciler Reconciler) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.mu.Lock()
+	c.cancel = cancel
+	c.mu.Unlock()
+	go c.run(ctx, reconciler)
+}
+
+// Stop stops the reconcile loop and all registered watchers and activators
+func (c *Controller) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel != nil {
+		c.cancel()
+	}
+	for _, w := range c.watchers {
+		w.Stop()
+	}
+	for _, a := range c.activators {
+		a.Stop()
+	}
+}
+
+func (c *Controller) run(ctx context.Context, reconciler Reconciler) {
+	active := true
+	for {
+		select {
+		case active = <-c.activeCh:
+		case id := <-c.queue:
+			if active {
+				c.reconcileOnce(ctx, reconciler, id)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reconcileOnce reconciles id once, requeueing it if the Reconciler reports
+// it isn't done yet
+func (c *Controller) reconcileOnce(ctx context.Context, reconciler Reconciler, id types.ID) {
+	succeeded, err := reconciler.Reconcile(ctx, id)
+	if err != nil {
+		log.Errorf("Failed reconciling %s: %s", id, err)
+	}
+	if err != nil || !succeeded {
+		go func() {
+			select {
+			case c.queue <- id:
+			case <-ctx.Done():
+			}
+		}()
+	}
+}
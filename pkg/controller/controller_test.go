@@ -0,0 +1,138 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/onosproject/onos-config/pkg/types"
+)
+
+// reconcilerFunc adapts a plain function to the Reconciler interface
+type reconcilerFunc func(ctx context.Context, id types.ID) (bool, error)
+
+func (f reconcilerFunc) Reconcile(ctx context.Context, id types.ID) (bool, error) {
+	return f(ctx, id)
+}
+
+// TestControllerRequeuesOnFailure verifies that an id is reconciled again
+// when the Reconciler returns (false, nil), and that reconciliation stops
+// once it reports success.
+func TestControllerRequeuesOnFailure(t *testing.T) {
+	c := NewController()
+	defer c.Stop()
+
+	var attempts int32
+	done := make(chan struct{})
+	c.Reconcile(reconcilerFunc(func(ctx context.Context, id types.ID) (bool, error) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return false, nil
+		}
+		close(done)
+		return true, nil
+	}))
+
+	c.queue <- types.ID("change-1")
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("id was not retried to success")
+	}
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&attempts), int32(3))
+}
+
+// TestControllerActiveGate verifies that the controller drops ids queued
+// while inactive instead of reconciling them, and resumes reconciling newly
+// queued ids once an Activator reports active again.
+func TestControllerActiveGate(t *testing.T) {
+	c := NewController()
+	defer c.Stop()
+
+	reconciled := make(chan types.ID, 1)
+	c.Reconcile(reconcilerFunc(func(ctx context.Context, id types.ID) (bool, error) {
+		reconciled <- id
+		return true, nil
+	}))
+
+	// Deactivate before queueing so the first select the run loop performs
+	// observes only activeCh ready, making the ordering deterministic
+	c.activeCh <- false
+	time.Sleep(50 * time.Millisecond)
+
+	c.queue <- types.ID("change-1")
+	select {
+	case <-reconciled:
+		t.Fatal("id was reconciled while controller was inactive")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	c.activeCh <- true
+	time.Sleep(50 * time.Millisecond)
+
+	c.queue <- types.ID("change-2")
+	select {
+	case id := <-reconciled:
+		assert.Equal(t, types.ID("change-2"), id)
+	case <-time.After(time.Second):
+		t.Fatal("id was not reconciled once the controller became active")
+	}
+}
+
+// fakeLeadershipStore is a minimal leadershipstore.Store fake used to drive
+// LeadershipActivator without depending on a real leader-election backend
+type fakeLeadershipStore struct {
+	initial bool
+	changes chan bool
+}
+
+func (s *fakeLeadershipStore) IsLeader(ctx context.Context) (bool, error) {
+	return s.initial, nil
+}
+
+func (s *fakeLeadershipStore) Watch(ctx context.Context, ch chan<- bool) error {
+	for {
+		select {
+		case v, ok := <-s.changes:
+			if !ok {
+				return nil
+			}
+			ch <- v
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// TestLeadershipActivatorForwardsState verifies that LeadershipActivator
+// reports the store's current leadership state on Start, then forwards
+// subsequent changes streamed from Watch.
+func TestLeadershipActivatorForwardsState(t *testing.T) {
+	store := &fakeLeadershipStore{initial: true, changes: make(chan bool, 1)}
+	activator := &LeadershipActivator{Store: store}
+	defer activator.Stop()
+
+	ch := make(chan bool, 2)
+	assert.NoError(t, activator.Start(ch))
+	assert.True(t, <-ch)
+
+	store.changes <- false
+	assert.False(t, <-ch)
+}
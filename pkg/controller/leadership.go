@@ -0,0 +1,58 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+
+	leadershipstore "github.com/onosproject/onos-config/pkg/store/leadership"
+)
+
+// LeadershipActivator activates the controller only while this replica holds
+// leadership in Store
+type LeadershipActivator struct {
+	Store  leadershipstore.Store
+	cancel context.CancelFunc
+}
+
+// Start reports the current leadership state and streams changes to ch
+// until Stop is called
+func (a *LeadershipActivator) Start(ch chan<- bool) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+
+	isLeader, err := a.Store.IsLeader(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+	ch <- isLeader
+
+	go func() {
+		if err := a.Store.Watch(ctx, ch); err != nil {
+			log.Errorf("Failed watching leadership: %s", err)
+		}
+	}()
+	return nil
+}
+
+// Stop stops watching leadership changes
+func (a *LeadershipActivator) Stop() {
+	if a.cancel != nil {
+		a.cancel()
+	}
+}
+
+var _ Activator = &LeadershipActivator{}
@@ -0,0 +1,184 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package device provides persistence for per-device changes
+package device
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	changetypes "github.com/onosproject/onos-config/pkg/types/change"
+	devicetypes "github.com/onosproject/onos-config/pkg/types/change/device"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// EventType indicates the kind of change a device-change Event reports
+type EventType int
+
+const (
+	// EventCreated indicates a device change was created
+	EventCreated EventType = iota
+	// EventUpdated indicates a device change was updated, e.g. its state
+	// transitioned
+	EventUpdated
+)
+
+// Event reports a change to a device change
+type Event struct {
+	Type   EventType
+	Change *devicetypes.Change
+}
+
+// Store manages the persistence and retrieval of device changes
+type Store interface {
+	// Get returns the device change with the given ID, or nil if none exists
+	Get(ctx context.Context, id devicetypes.ID) (*devicetypes.Change, error)
+
+	// GetByKey returns the device change with the given idempotency key, or
+	// nil if none exists. Callers that lose a Create race to ErrAlreadyExists
+	// use this to look up the change that was actually persisted
+	GetByKey(ctx context.Context, key string) (*devicetypes.Change, error)
+
+	// Create creates a new device change, assigning it an ID and index. If a
+	// change with the same Key already exists, it returns an error
+	// satisfying errors.IsAlreadyExists and leaves the existing change
+	// untouched; callers should look it up with GetByKey
+	Create(ctx context.Context, change *devicetypes.Change) error
+
+	// Update updates an existing device change. If change.Revision does not
+	// match the stored revision, it returns an error satisfying
+	// errors.IsConflict rather than overwriting the concurrent update
+	Update(ctx context.Context, change *devicetypes.Change) error
+
+	// Watch streams device-change events to ch until ctx is done
+	Watch(ctx context.Context, ch chan<- Event) error
+}
+
+// NewLocalStore returns a new in-memory Store
+func NewLocalStore() Store {
+	return &localStore{
+		changes: make(map[devicetypes.ID]*devicetypes.Change),
+		byKey:   make(map[string]devicetypes.ID),
+	}
+}
+
+type localStore struct {
+	mu          sync.Mutex
+	changes     map[devicetypes.ID]*devicetypes.Change
+	byKey       map[string]devicetypes.ID
+	nextID      uint64
+	subscribers []chan<- Event
+}
+
+func (s *localStore) Get(ctx context.Context, id devicetypes.ID) (*devicetypes.Change, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change, ok := s.changes[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *change
+	return &copied, nil
+}
+
+func (s *localStore) GetByKey(ctx context.Context, key string) (*devicetypes.Change, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.byKey[key]
+	if !ok {
+		return nil, nil
+	}
+	copied := *s.changes[id]
+	return &copied, nil
+}
+
+func (s *localStore) Create(ctx context.Context, change *devicetypes.Change) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byKey[change.Key]; ok {
+		return errors.NewAlreadyExists("device change with key %s already exists", change.Key)
+	}
+	s.nextID++
+	change.ID = devicetypes.ID(fmt.Sprintf("%s-%d", change.DeviceID, s.nextID))
+	change.Index = changetypes.Index(s.nextID)
+	change.Revision = 1
+	copied := *change
+	s.changes[change.ID] = &copied
+	s.byKey[change.Key] = change.ID
+	s.publishLocked(Event{Type: EventCreated, Change: &copied})
+	return nil
+}
+
+func (s *localStore) Update(ctx context.Context, change *devicetypes.Change) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.changes[change.ID]
+	if !ok {
+		return errors.NewNotFound("device change %s not found", change.ID)
+	}
+	if change.Revision != stored.Revision {
+		return errors.NewConflict("device change %s was updated concurrently", change.ID)
+	}
+	change.Revision++
+	copied := *change
+	s.changes[change.ID] = &copied
+	s.publishLocked(Event{Type: EventUpdated, Change: &copied})
+	return nil
+}
+
+// Watch registers ch to receive device-change events until ctx is done.
+// Events are delivered best-effort: a subscriber that isn't keeping up has
+// events dropped rather than blocking the store
+func (s *localStore) Watch(ctx context.Context, ch chan<- Event) error {
+	s.mu.Lock()
+	s.subscribers = append(s.subscribers, ch)
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, subscriber := range s.subscribers {
+			if subscriber == ch {
+				s.subscribers = append(s.subscribers[:i], s.subscribers[i+1:]...)
+				break
+			}
+		}
+	}()
+	return nil
+}
+
+// publishLocked delivers event to all subscribers. Callers must hold s.mu
+func (s *localStore) publishLocked(event Event) {
+	for _, subscriber := range s.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package device
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	devicetypes "github.com/onosproject/onos-config/pkg/types/change/device"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+func TestCreateAssignsIDAndIsIdempotentByKey(t *testing.T) {
+	s := NewLocalStore()
+
+	change := &devicetypes.Change{Key: "device-1:resync:5", DeviceID: "device-1"}
+	assert.NoError(t, s.Create(context.Background(), change))
+	assert.NotEmpty(t, change.ID)
+
+	duplicate := &devicetypes.Change{Key: "device-1:resync:5", DeviceID: "device-1"}
+	err := s.Create(context.Background(), duplicate)
+	assert.True(t, errors.IsAlreadyExists(err))
+
+	existing, err := s.GetByKey(context.Background(), "device-1:resync:5")
+	assert.NoError(t, err)
+	assert.Equal(t, change.ID, existing.ID)
+}
+
+func TestUpdateRejectsStaleRevision(t *testing.T) {
+	s := NewLocalStore()
+	change := &devicetypes.Change{Key: "device-1:change-1", DeviceID: "device-1"}
+	assert.NoError(t, s.Create(context.Background(), change))
+
+	stale := &devicetypes.Change{ID: change.ID, DeviceID: "device-1", Revision: 0}
+	err := s.Update(context.Background(), stale)
+	assert.True(t, errors.IsConflict(err))
+}
+
+func TestUpdateNotFound(t *testing.T) {
+	s := NewLocalStore()
+	err := s.Update(context.Background(), &devicetypes.Change{ID: "missing"})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestWatchDeliversCreateAndUpdateEvents(t *testing.T) {
+	s := NewLocalStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 2)
+	assert.NoError(t, s.Watch(ctx, events))
+
+	change := &devicetypes.Change{Key: "device-1:change-1", DeviceID: "device-1"}
+	assert.NoError(t, s.Create(ctx, change))
+
+	select {
+	case event := <-events:
+		assert.Equal(t, EventCreated, event.Type)
+	case <-time.After(time.Second):
+		t.Fatal("create event was not delivered")
+	}
+
+	assert.NoError(t, s.Update(ctx, change))
+
+	select {
+	case event := <-events:
+		assert.Equal(t, EventUpdated, event.Type)
+	case <-time.After(time.Second):
+		t.Fatal("update event was not delivered")
+	}
+}
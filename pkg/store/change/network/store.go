@@ -0,0 +1,189 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package network provides persistence for NetworkChanges
+package network
+
+import (
+	"context"
+	"sync"
+
+	networktypes "github.com/onosproject/onos-config/pkg/types/change/network"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// EventType indicates the kind of change a network-change Event reports
+type EventType int
+
+const (
+	// EventCreated indicates a network change was created
+	EventCreated EventType = iota
+	// EventUpdated indicates a network change was updated, e.g. its state
+	// transitioned
+	EventUpdated
+)
+
+// Event reports a change to a network change
+type Event struct {
+	Type   EventType
+	Change *networktypes.NetworkChange
+}
+
+// Store manages the persistence and retrieval of NetworkChanges
+type Store interface {
+	// Get returns the network change with the given ID, or nil if none exists
+	Get(ctx context.Context, id networktypes.ID) (*networktypes.NetworkChange, error)
+
+	// GetByIndex returns the network change at the given index, or nil if none exists
+	GetByIndex(ctx context.Context, index networktypes.Index) (*networktypes.NetworkChange, error)
+
+	// Create creates a new network change, assigning it the next index
+	Create(ctx context.Context, change *networktypes.NetworkChange) error
+
+	// Update updates an existing network change. If change.Revision does not
+	// match the stored revision, it returns an error satisfying
+	// errors.IsConflict rather than overwriting the concurrent update
+	Update(ctx context.Context, change *networktypes.NetworkChange) error
+
+	// LastIndex returns the index of the most recently created network change
+	LastIndex(ctx context.Context) (networktypes.Index, error)
+
+	// Watch streams network-change events to ch until ctx is done
+	Watch(ctx context.Context, ch chan<- Event) error
+}
+
+// NewLocalStore returns a new in-memory Store
+func NewLocalStore() Store {
+	return &localStore{
+		changes: make(map[networktypes.ID]*networktypes.NetworkChange),
+		byIndex: make(map[networktypes.Index]*networktypes.NetworkChange),
+	}
+}
+
+type localStore struct {
+	mu          sync.Mutex
+	changes     map[networktypes.ID]*networktypes.NetworkChange
+	byIndex     map[networktypes.Index]*networktypes.NetworkChange
+	lastIndex   networktypes.Index
+	subscribers []chan<- Event
+}
+
+func (s *localStore) Get(ctx context.Context, id networktypes.ID) (*networktypes.NetworkChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change, ok := s.changes[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *change
+	return &copied, nil
+}
+
+func (s *localStore) GetByIndex(ctx context.Context, index networktypes.Index) (*networktypes.NetworkChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change, ok := s.byIndex[index]
+	if !ok {
+		return nil, nil
+	}
+	copied := *change
+	return &copied, nil
+}
+
+func (s *localStore) Create(ctx context.Context, change *networktypes.NetworkChange) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.changes[change.ID]; ok {
+		return errors.NewAlreadyExists("network change %s already exists", change.ID)
+	}
+	s.lastIndex++
+	change.Index = s.lastIndex
+	change.Revision = 1
+	copied := *change
+	s.changes[change.ID] = &copied
+	s.byIndex[change.Index] = &copied
+	s.publishLocked(Event{Type: EventCreated, Change: &copied})
+	return nil
+}
+
+func (s *localStore) Update(ctx context.Context, change *networktypes.NetworkChange) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.changes[change.ID]
+	if !ok {
+		return errors.NewNotFound("network change %s not found", change.ID)
+	}
+	if change.Revision != stored.Revision {
+		return errors.NewConflict("network change %s was updated concurrently", change.ID)
+	}
+	change.Revision++
+	copied := *change
+	s.changes[change.ID] = &copied
+	s.byIndex[change.Index] = &copied
+	s.publishLocked(Event{Type: EventUpdated, Change: &copied})
+	return nil
+}
+
+func (s *localStore) LastIndex(ctx context.Context) (networktypes.Index, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastIndex, nil
+}
+
+// Watch registers ch to receive network-change events until ctx is done.
+// Events are delivered best-effort: a subscriber that isn't keeping up has
+// events dropped rather than blocking the store
+func (s *localStore) Watch(ctx context.Context, ch chan<- Event) error {
+	s.mu.Lock()
+	s.subscribers = append(s.subscribers, ch)
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, subscriber := range s.subscribers {
+			if subscriber == ch {
+				s.subscribers = append(s.subscribers[:i], s.subscribers[i+1:]...)
+				break
+			}
+		}
+	}()
+	return nil
+}
+
+// publishLocked delivers event to all subscribers. Callers must hold s.mu
+func (s *localStore) publishLocked(event Event) {
+	for _, subscriber := range s.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	networktypes "github.com/onosproject/onos-config/pkg/types/change/network"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+func TestCreateAssignsSequentialIndex(t *testing.T) {
+	s := NewLocalStore()
+
+	first := &networktypes.NetworkChange{ID: "change-1"}
+	assert.NoError(t, s.Create(context.Background(), first))
+	assert.Equal(t, networktypes.Index(1), first.Index)
+
+	second := &networktypes.NetworkChange{ID: "change-2"}
+	assert.NoError(t, s.Create(context.Background(), second))
+	assert.Equal(t, networktypes.Index(2), second.Index)
+
+	lastIndex, err := s.LastIndex(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, networktypes.Index(2), lastIndex)
+
+	byIndex, err := s.GetByIndex(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, networktypes.ID("change-1"), byIndex.ID)
+}
+
+func TestCreateRejectsDuplicateID(t *testing.T) {
+	s := NewLocalStore()
+	change := &networktypes.NetworkChange{ID: "change-1"}
+	assert.NoError(t, s.Create(context.Background(), change))
+
+	err := s.Create(context.Background(), &networktypes.NetworkChange{ID: "change-1"})
+	assert.True(t, errors.IsAlreadyExists(err))
+}
+
+func TestUpdateRejectsStaleRevision(t *testing.T) {
+	s := NewLocalStore()
+	change := &networktypes.NetworkChange{ID: "change-1"}
+	assert.NoError(t, s.Create(context.Background(), change))
+
+	stale := &networktypes.NetworkChange{ID: "change-1", Index: change.Index, Revision: 0}
+	err := s.Update(context.Background(), stale)
+	assert.True(t, errors.IsConflict(err))
+}
+
+func TestWatchDeliversCreateAndUpdateEvents(t *testing.T) {
+	s := NewLocalStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 2)
+	assert.NoError(t, s.Watch(ctx, events))
+
+	change := &networktypes.NetworkChange{ID: "change-1"}
+	assert.NoError(t, s.Create(ctx, change))
+
+	select {
+	case event := <-events:
+		assert.Equal(t, EventCreated, event.Type)
+	case <-time.After(time.Second):
+		t.Fatal("create event was not delivered")
+	}
+
+	assert.NoError(t, s.Update(ctx, change))
+
+	select {
+	case event := <-events:
+		assert.Equal(t, EventUpdated, event.Type)
+	case <-time.After(time.Second):
+		t.Fatal("update event was not delivered")
+	}
+}
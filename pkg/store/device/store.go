@@ -0,0 +1,132 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package device provides access to the device topology
+package device
+
+import (
+	"context"
+	"sync"
+
+	devicetopo "github.com/onosproject/onos-config/pkg/types/device"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// EventType indicates the kind of change a device Event reports
+type EventType int
+
+const (
+	// EventUpdated indicates a device was created or updated
+	EventUpdated EventType = iota
+)
+
+// Event reports a change to a device's topology state
+type Event struct {
+	Type   EventType
+	Device *devicetopo.Device
+}
+
+// Store manages the device topology
+type Store interface {
+	// Get returns the device with the given ID, or nil if none exists
+	Get(ctx context.Context, id devicetopo.ID) (*devicetopo.Device, error)
+
+	// Update updates an existing device, e.g. to advance its
+	// LastResyncIndex cursor. If device.Revision does not match the stored
+	// revision, it returns an error satisfying errors.IsConflict rather than
+	// overwriting a concurrent update
+	Update(ctx context.Context, device *devicetopo.Device) error
+
+	// Watch streams device topology events to ch until ctx is done
+	Watch(ctx context.Context, ch chan<- Event) error
+}
+
+// NewLocalStore returns a new in-memory Store
+func NewLocalStore() Store {
+	return &localStore{
+		devices: make(map[devicetopo.ID]*devicetopo.Device),
+	}
+}
+
+type localStore struct {
+	mu          sync.Mutex
+	devices     map[devicetopo.ID]*devicetopo.Device
+	subscribers []chan<- Event
+}
+
+func (s *localStore) Get(ctx context.Context, id devicetopo.ID) (*devicetopo.Device, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	device, ok := s.devices[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *device
+	return &copied, nil
+}
+
+func (s *localStore) Update(ctx context.Context, device *devicetopo.Device) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.devices[device.ID]
+	if !ok {
+		return errors.NewNotFound("device %s not found", device.ID)
+	}
+	if device.Revision != stored.Revision {
+		return errors.NewConflict("device %s was updated concurrently", device.ID)
+	}
+	device.Revision++
+	copied := *device
+	s.devices[device.ID] = &copied
+	s.publishLocked(Event{Type: EventUpdated, Device: &copied})
+	return nil
+}
+
+// Watch registers ch to receive device topology events until ctx is done.
+// Events are delivered best-effort: a subscriber that isn't keeping up has
+// events dropped rather than blocking the store
+func (s *localStore) Watch(ctx context.Context, ch chan<- Event) error {
+	s.mu.Lock()
+	s.subscribers = append(s.subscribers, ch)
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, subscriber := range s.subscribers {
+			if subscriber == ch {
+				s.subscribers = append(s.subscribers[:i], s.subscribers[i+1:]...)
+				break
+			}
+		}
+	}()
+	return nil
+}
+
+// publishLocked delivers event to all subscribers. Callers must hold s.mu
+func (s *localStore) publishLocked(event Event) {
+	for _, subscriber := range s.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package device
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	changetypes "github.com/onosproject/onos-config/pkg/types/change"
+	devicetopo "github.com/onosproject/onos-config/pkg/types/device"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// seed inserts a device directly into the store's map, bypassing Update,
+// since Store has no Create method
+func seed(s Store, device *devicetopo.Device) {
+	local := s.(*localStore)
+	local.mu.Lock()
+	defer local.mu.Unlock()
+	local.devices[device.ID] = device
+}
+
+func TestUpdateRejectsStaleRevision(t *testing.T) {
+	s := NewLocalStore()
+	seed(s, &devicetopo.Device{ID: "device-1", State: devicetopo.DeviceState_DEVICE_CONNECTED, Revision: 1})
+
+	stale := &devicetopo.Device{ID: "device-1", State: devicetopo.DeviceState_DEVICE_RECONCILING, Revision: 0}
+	err := s.Update(context.Background(), stale)
+	assert.True(t, errors.IsConflict(err))
+
+	current, err := s.Get(context.Background(), "device-1")
+	assert.NoError(t, err)
+	assert.Equal(t, devicetopo.DeviceState_DEVICE_CONNECTED, current.State)
+}
+
+func TestUpdateBumpsRevisionOnSuccess(t *testing.T) {
+	s := NewLocalStore()
+	seed(s, &devicetopo.Device{ID: "device-1", Revision: 1})
+
+	update := &devicetopo.Device{ID: "device-1", LastResyncIndex: 5, Revision: 1}
+	assert.NoError(t, s.Update(context.Background(), update))
+
+	current, err := s.Get(context.Background(), "device-1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), current.Revision)
+	assert.Equal(t, changetypes.Index(5), current.LastResyncIndex)
+}
+
+func TestUpdateNotFound(t *testing.T) {
+	s := NewLocalStore()
+	err := s.Update(context.Background(), &devicetopo.Device{ID: "missing"})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestWatchDeliversUpdateEvent(t *testing.T) {
+	s := NewLocalStore()
+	seed(s, &devicetopo.Device{ID: "device-1", Revision: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 1)
+	assert.NoError(t, s.Watch(ctx, events))
+
+	assert.NoError(t, s.Update(ctx, &devicetopo.Device{ID: "device-1", State: devicetopo.DeviceState_DEVICE_CONNECTED, Revision: 1}))
+
+	select {
+	case event := <-events:
+		assert.Equal(t, EventUpdated, event.Type)
+		assert.Equal(t, devicetopo.ID("device-1"), event.Device.ID)
+	case <-time.After(time.Second):
+		t.Fatal("update event was not delivered")
+	}
+}
+
+func TestWatchDropsEventsForSlowSubscriber(t *testing.T) {
+	s := NewLocalStore()
+	seed(s, &devicetopo.Device{ID: "device-1", Revision: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Unbuffered and never read from: the store must not block publishing
+	// to it
+	events := make(chan Event)
+	assert.NoError(t, s.Watch(ctx, events))
+
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, s.Update(ctx, &devicetopo.Device{ID: "device-1", Revision: 1}))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Update blocked on a slow subscriber instead of dropping the event")
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package leadership provides access to the leader election used to gate
+// controllers that must run on a single replica at a time
+package leadership
+
+import "context"
+
+// Store tracks which replica currently holds leadership for a given topic
+type Store interface {
+	// IsLeader returns whether this replica currently holds leadership
+	IsLeader(ctx context.Context) (bool, error)
+
+	// Watch streams leadership changes to the given channel until ctx is done
+	Watch(ctx context.Context, ch chan<- bool) error
+}
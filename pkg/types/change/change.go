@@ -0,0 +1,69 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package change defines types shared by both network and device changes
+package change
+
+// Index is a monotonically increasing, store-assigned sequence number for a
+// change, used to order changes and to resume iteration over change history
+type Index uint64
+
+// Phase indicates which direction a change is being driven in
+type Phase int
+
+const (
+	// Phase_CHANGE indicates the change is being applied
+	Phase_CHANGE Phase = iota
+	// Phase_ROLLBACK indicates the change is being reverted
+	Phase_ROLLBACK
+	// Phase_RESYNC indicates a device's committed configuration is being
+	// replayed to it after it reconnects, rather than a new change being
+	// applied or an existing one reverted
+	Phase_RESYNC
+)
+
+// State indicates a change's progress within its current phase
+type State int
+
+const (
+	// State_PENDING indicates the change has not yet started
+	State_PENDING State = iota
+	// State_RUNNING indicates the change is being applied
+	State_RUNNING
+	// State_COMPLETE indicates the change has completed successfully
+	State_COMPLETE
+	// State_FAILED indicates the change failed to apply
+	State_FAILED
+)
+
+// Reason qualifies why a change is not progressing
+type Reason int
+
+const (
+	// Reason_ERROR indicates the change cannot progress due to an error
+	Reason_ERROR Reason = iota
+	// Reason_PhaseChangeRefused indicates the change cannot progress yet
+	// because a precondition - such as a target device becoming ready - has
+	// not been satisfied. Unlike Reason_ERROR, it is expected to clear on
+	// its own and does not indicate the change has failed
+	Reason_PhaseChangeRefused
+)
+
+// Status is the status common to both network and device changes
+type Status struct {
+	Phase   Phase
+	State   State
+	Reason  Reason
+	Message string
+}
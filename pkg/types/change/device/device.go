@@ -0,0 +1,49 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package device defines the per-device slice of a network change
+package device
+
+import (
+	"github.com/onosproject/onos-config/pkg/types"
+	changetypes "github.com/onosproject/onos-config/pkg/types/change"
+	devicetopo "github.com/onosproject/onos-config/pkg/types/device"
+)
+
+// ID is the unique identifier of a device change
+type ID string
+
+// PathValue is a single configuration path/value pair
+type PathValue struct {
+	Path  string
+	Value []byte
+}
+
+// Change is the portion of a NetworkChange that applies to a single device
+type Change struct {
+	ID ID
+	// Key uniquely identifies the change independent of the store-assigned
+	// ID, so that retrying its creation after losing a revision race, or
+	// after a resync, is idempotent rather than creating a duplicate
+	Key             string
+	Index           changetypes.Index
+	NetworkChangeID types.ID
+	DeviceID        devicetopo.ID
+	DeviceVersion   string
+	Values          []*PathValue
+	// Revision is bumped by the store on every successful Update and used to
+	// detect lost updates, the same way as on NetworkChange
+	Revision uint64
+	Status   changetypes.Status
+}
@@ -0,0 +1,52 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package network defines the top-level network-wide change model
+package network
+
+import (
+	changetypes "github.com/onosproject/onos-config/pkg/types/change"
+	devicetypes "github.com/onosproject/onos-config/pkg/types/change/device"
+	devicetopo "github.com/onosproject/onos-config/pkg/types/device"
+)
+
+// ID is the unique identifier of a network change
+type ID string
+
+// Index is an alias of changetypes.Index so callers throughout the config
+// controllers can refer to it without importing the change package directly
+type Index = changetypes.Index
+
+// ChangeValue is the change requested for a single device within a
+// NetworkChange
+type ChangeValue struct {
+	ID            devicetypes.ID
+	Index         changetypes.Index
+	DeviceID      devicetopo.ID
+	DeviceVersion string
+	Values        []*devicetypes.PathValue
+}
+
+// NetworkChange is a change spanning one or more devices
+type NetworkChange struct {
+	ID      ID
+	Index   changetypes.Index
+	Changes []*ChangeValue
+	// Revision is bumped by the store on every successful Update and used to
+	// detect lost updates: a caller that read a stale Revision has its Update
+	// rejected with a conflict error (errors.IsConflict) rather than silently overwriting a concurrent
+	// change
+	Revision uint64
+	Status   changetypes.Status
+}
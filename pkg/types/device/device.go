@@ -0,0 +1,62 @@
+// Copyright 2019-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package device defines the device topology model consulted by the config
+// controllers when deciding whether a device is ready to receive a change
+package device
+
+import (
+	changetypes "github.com/onosproject/onos-config/pkg/types/change"
+)
+
+// ID is the unique identifier of a device
+type ID string
+
+// DeviceState is the connectivity/lifecycle state of a device
+type DeviceState int
+
+const (
+	// DeviceState_DEVICE_UNKNOWN is the zero value, used for devices whose
+	// state has not yet been reported
+	DeviceState_DEVICE_UNKNOWN DeviceState = iota
+	// DeviceState_DEVICE_CONNECTED indicates the device is connected and
+	// able to receive changes
+	DeviceState_DEVICE_CONNECTED
+	// DeviceState_DEVICE_DELETING indicates the device is being removed from
+	// the topology; changes targeting it can never be applied
+	DeviceState_DEVICE_DELETING
+	// DeviceState_DEVICE_RECONCILING indicates the device adapter is
+	// resyncing its southbound session and is temporarily unable to accept
+	// changes
+	DeviceState_DEVICE_RECONCILING
+	// DeviceState_DEVICE_UNAVAILABLE indicates the device is momentarily
+	// unreachable
+	DeviceState_DEVICE_UNAVAILABLE
+)
+
+// Device is a device known to the topology
+type Device struct {
+	ID      ID
+	Version string
+	State   DeviceState
+	// LastResyncIndex is the highest network change index whose effective
+	// configuration has been replayed to this device. It is kept across
+	// reconnects and advances only past changes that have settled (COMPLETE
+	// or FAILED), so a change still PENDING or RUNNING when the device
+	// reconnects is picked up on a later resync once it settles
+	LastResyncIndex changetypes.Index
+	// Revision is bumped by the store on every successful Update and used to
+	// detect lost updates, the same way as on NetworkChange
+	Revision uint64
+}